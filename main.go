@@ -2,12 +2,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
@@ -23,42 +32,105 @@ type Options struct {
 	interval    float64
 	shellMode   bool
 	color       string
+	parallel    int
+	keepOrder   bool
+	timeout     time.Duration
+	nulDelim    bool
+	delimiter   string
+	reportFile   string
+	reportFormat string
+	retry         int
+	retryBackoff  time.Duration
+	retryOn       string
+	retryOnCodes  []int
+	killGrace     time.Duration
 }
 
 // CommandExecutor interface for command execution abstraction
 type CommandExecutor interface {
 	ExecuteShell(command string) int
 	ExecuteDirect(args []string) int
+	ExecuteShellCtx(ctx context.Context, command string) int
+	ExecuteDirectCtx(ctx context.Context, args []string) int
+}
+
+// timeoutExitCode is the exit code reported when a command is killed for
+// exceeding its --timeout, mirroring GNU timeout(1).
+const timeoutExitCode = 124
+
+// resolveExitCode maps a finished command's context/error state to a
+// process exit code, analogous to wrapcommander.ResolveExitCode: a
+// deadline-exceeded context, or a child killed by SIGKILL/SIGTERM (as
+// runInProcessGroup sends on cancellation), reports as a timeout rather
+// than its raw ExitError code.
+func resolveExitCode(ctx context.Context, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return timeoutExitCode
+	}
+
+	if exitError, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			switch status.Signal() {
+			case syscall.SIGKILL, syscall.SIGTERM:
+				return timeoutExitCode
+			}
+		}
+		return exitError.ExitCode()
+	}
+
+	return 1
+}
+
+// BufferedExecutor is implemented by executors that can produce a job-scoped
+// copy bound to dedicated output streams. processStdinParallel uses this to
+// give each concurrent worker its own stdout/stderr so that output from
+// simultaneous sh -c invocations never interleaves.
+type BufferedExecutor interface {
+	WithOutput(stdout, stderr io.Writer) CommandExecutor
 }
 
 // RealCommandExecutor implements CommandExecutor using actual system commands
 type RealCommandExecutor struct {
-	stdout io.Writer
-	stderr io.Writer
+	stdout    io.Writer
+	stderr    io.Writer
+	killGrace time.Duration
 }
 
-func NewRealCommandExecutor(stdout, stderr io.Writer) *RealCommandExecutor {
+func NewRealCommandExecutor(stdout, stderr io.Writer, killGrace time.Duration) *RealCommandExecutor {
 	return &RealCommandExecutor{
-		stdout: stdout,
-		stderr: stderr,
+		stdout:    stdout,
+		stderr:    stderr,
+		killGrace: killGrace,
 	}
 }
 
+// WithOutput returns a new RealCommandExecutor bound to the given output
+// streams, letting callers (e.g. the parallel worker pool) isolate each
+// job's output before flushing it to the shared destination.
+func (e *RealCommandExecutor) WithOutput(stdout, stderr io.Writer) CommandExecutor {
+	return NewRealCommandExecutor(stdout, stderr, e.killGrace)
+}
+
 func (e *RealCommandExecutor) ExecuteShell(command string) int {
+	return e.ExecuteShellCtx(context.Background(), command)
+}
+
+func (e *RealCommandExecutor) ExecuteShellCtx(ctx context.Context, command string) int {
 	cmd := exec.Command("sh", "-c", command)
 	cmd.Stdout = e.stdout
 	cmd.Stderr = e.stderr
-	
-	if err := cmd.Run(); err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return exitError.ExitCode()
-		}
-		return 1
-	}
-	return 0
+	return runInProcessGroup(ctx, cmd, e.killGrace)
 }
 
 func (e *RealCommandExecutor) ExecuteDirect(args []string) int {
+	return e.ExecuteDirectCtx(context.Background(), args)
+}
+
+func (e *RealCommandExecutor) ExecuteDirectCtx(ctx context.Context, args []string) int {
 	if len(args) == 0 {
 		return 0
 	}
@@ -66,14 +138,148 @@ func (e *RealCommandExecutor) ExecuteDirect(args []string) int {
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdout = e.stdout
 	cmd.Stderr = e.stderr
-	
-	if err := cmd.Run(); err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return exitError.ExitCode()
+	return runInProcessGroup(ctx, cmd, e.killGrace)
+}
+
+// runInProcessGroup starts cmd in its own process group so that, on
+// cancellation, the signal below reaches every process the command may
+// have spawned rather than just cmd itself. If ctx is canceled (by
+// --timeout or by main's shutdown handling) it sends SIGTERM to the whole
+// group, waits up to killGrace for the group to exit on its own, and
+// escalates to SIGKILL if it hasn't.
+func runInProcessGroup(ctx context.Context, cmd *exec.Cmd, killGrace time.Duration) int {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return resolveExitCode(ctx, err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return resolveExitCode(ctx, err)
+	case <-ctx.Done():
+		signalProcessGroup(cmd, syscall.SIGTERM)
+		select {
+		case err := <-done:
+			return resolveExitCode(ctx, err)
+		case <-time.After(killGrace):
+			signalProcessGroup(cmd, syscall.SIGKILL)
+			return resolveExitCode(ctx, <-done)
 		}
-		return 1
 	}
-	return 0
+}
+
+// signalProcessGroup forwards sig to every process in cmd's group (the
+// negative pid convention for kill(2)).
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// ReportEntry is the structured record --report writes for a single input
+// line: what was run, when, how long it took, and how it concluded.
+type ReportEntry struct {
+	Line       string      `json:"line"`
+	Command    interface{} `json:"command,omitempty"`
+	StartTime  time.Time   `json:"start_time,omitempty"`
+	EndTime    time.Time   `json:"end_time,omitempty"`
+	DurationMs int64       `json:"duration_ms,omitempty"`
+	ExitCode   int         `json:"exit_code"`
+	Skipped    bool        `json:"skipped,omitempty"`
+	DryRun     bool        `json:"dry_run,omitempty"`
+	TimedOut   bool        `json:"timed_out,omitempty"`
+}
+
+// reportSummary is the final aggregate object written once processing
+// finishes, regardless of --report-format.
+type reportSummary struct {
+	OK              int `json:"ok"`
+	Fail            int `json:"fail"`
+	Skipped         int `json:"skipped"`
+	HighestExitCode int `json:"highest_exit_code"`
+}
+
+// Reporter records one ReportEntry per processed input line and, once
+// closed, writes a final summary object with ok/fail/skipped counts and
+// the highest exit code seen. In "ndjson" format entries are written as
+// they arrive, one JSON object per line, so a run under -P streams an
+// auditable log as jobs complete; "json" format instead buffers entries
+// and emits a single JSON document from Close.
+type Reporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  string
+	entries []ReportEntry
+	summary reportSummary
+}
+
+// NewReporter creates a Reporter that writes to w using the given
+// --report-format ("json" or "ndjson").
+func NewReporter(w io.Writer, format string) *Reporter {
+	return &Reporter{w: w, format: format}
+}
+
+// Record tallies entry into the running summary and, for ndjson, writes it
+// immediately. It is safe to call concurrently from parallel workers.
+func (r *Reporter) Record(entry ReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case entry.Skipped:
+		r.summary.Skipped++
+	case entry.ExitCode != 0:
+		r.summary.Fail++
+	default:
+		r.summary.OK++
+	}
+	if entry.ExitCode > r.summary.HighestExitCode {
+		r.summary.HighestExitCode = entry.ExitCode
+	}
+
+	if r.format == "json" {
+		r.entries = append(r.entries, entry)
+		return
+	}
+
+	if data, err := json.Marshal(entry); err == nil {
+		r.w.Write(data)
+		r.w.Write([]byte("\n"))
+	}
+}
+
+// Close writes the final summary object (plus, for "json" format, the
+// buffered entries) and must be called once processing has finished.
+func (r *Reporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.format == "json" {
+		payload := struct {
+			Entries []ReportEntry `json:"entries"`
+			Summary reportSummary `json:"summary"`
+		}{r.entries, r.summary}
+		data, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = r.w.Write(append(data, '\n'))
+		return err
+	}
+
+	data, err := json.Marshal(struct {
+		Summary reportSummary `json:"summary"`
+	}{r.summary})
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(data, '\n'))
+	return err
 }
 
 // App holds the application state and dependencies
@@ -82,9 +288,18 @@ type App struct {
 	stdout   io.Writer
 	stderr   io.Writer
 	executor CommandExecutor
+	reporter *Reporter
 	useColor bool
 }
 
+// report records entry if a --report destination is configured; it is a
+// no-op otherwise so call sites don't need to guard every call.
+func (app *App) report(entry ReportEntry) {
+	if app.reporter != nil {
+		app.reporter.Record(entry)
+	}
+}
+
 // shouldUseColor determines whether to use color output based on options and environment
 func shouldUseColor(colorOption string, stderr io.Writer) bool {
 	// NO_COLOR environment variable takes precedence
@@ -169,6 +384,18 @@ func main() {
 	flag.Float64Var(&opts.interval, "t", 0, "interval between commands in seconds")
 	flag.BoolVar(&opts.shellMode, "c", false, "shell mode")
 	flag.StringVar(&opts.color, "color", "auto", "color output (never/always/auto)")
+	flag.IntVar(&opts.parallel, "P", 1, "run up to N commands in parallel")
+	flag.IntVar(&opts.parallel, "parallel", 1, "run up to N commands in parallel")
+	flag.BoolVar(&opts.keepOrder, "keep-order", false, "preserve input order when flushing output in parallel mode")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "kill each command if it runs longer than this duration, e.g. 30s (0 = no timeout)")
+	flag.BoolVar(&opts.nulDelim, "0", false, "read NUL-terminated records instead of newline-terminated lines")
+	flag.StringVar(&opts.delimiter, "d", " ", "field delimiter for {1}, {2}, ... placeholders (default: whitespace)")
+	flag.StringVar(&opts.reportFile, "report", "", "write a structured per-command run report to FILE")
+	flag.StringVar(&opts.reportFormat, "report-format", "ndjson", "report format (json/ndjson)")
+	flag.IntVar(&opts.retry, "retry", 0, "retry a failed command up to N additional times")
+	flag.DurationVar(&opts.retryBackoff, "retry-backoff", 0, "base backoff between retries; actual wait is backoff * 2^attempt, capped")
+	flag.StringVar(&opts.retryOn, "retry-on", "", "comma-separated exit codes to retry on (default: any non-zero)")
+	flag.DurationVar(&opts.killGrace, "kill-grace", 5*time.Second, "time to wait after SIGTERM before SIGKILLing a still-running command")
 
 	flag.Parse()
 
@@ -194,33 +421,189 @@ func main() {
 		}
 	}
 
+	if err := validateOptions(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	retryOnCodes, err := parseRetryOn(opts.retryOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.retryOnCodes = retryOnCodes
+
 	app := &App{
 		stdin:    os.Stdin,
 		stdout:   os.Stdout,
 		stderr:   os.Stderr,
-		executor: NewRealCommandExecutor(os.Stdout, os.Stderr),
+		executor: NewRealCommandExecutor(os.Stdout, os.Stderr, opts.killGrace),
 		useColor: shouldUseColor(opts.color, os.Stderr),
 	}
 
-	err := app.processStdin(opts, args)
+	var reportWriter *os.File
+	if opts.reportFile != "" {
+		var err error
+		reportWriter, err = os.Create(opts.reportFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to open --report file: %v\n", err)
+			os.Exit(1)
+		}
+		app.reporter = NewReporter(reportWriter, opts.reportFormat)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		app.printWarning("\n[received signal, terminating in-flight commands (press again to abort immediately)]\n")
+		cancel()
+		<-sigCh
+		app.printWarning("[received second signal, aborting immediately]\n")
+		os.Exit(130)
+	}()
+
+	err = app.processStdin(ctx, opts, args)
+
+	if app.reporter != nil {
+		app.reporter.Close()
+		reportWriter.Close()
+	}
+
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func (app *App) processStdin(opts Options, args []string) error {
-	scanner := bufio.NewScanner(app.stdin)
+// commandContext returns a context derived from parent and bound by
+// opts.timeout, along with the cancel func that must be called once the
+// command finishes. parent is expected to be main's shutdown context, so
+// that a SIGINT/SIGTERM cancels in-flight and not-yet-started commands
+// alike even when no --timeout is configured.
+func commandContext(parent context.Context, opts Options) (context.Context, context.CancelFunc) {
+	if opts.timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, opts.timeout)
+}
+
+// parseRetryOn parses the comma-separated --retry-on exit code list. An
+// empty string (the default) means "retry on any non-zero exit code".
+func parseRetryOn(spec string) ([]int, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var codes []int
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		code, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --retry-on exit code %q: %v", field, err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// shouldRetry reports whether exitCode qualifies for a retry under
+// retryOn; an empty retryOn means any non-zero exit code is retryable.
+func shouldRetry(exitCode int, retryOn []int) bool {
+	if exitCode == 0 {
+		return false
+	}
+	if len(retryOn) == 0 {
+		return true
+	}
+	for _, code := range retryOn {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRetryBackoff caps the exponential backoff between retries so a large
+// --retry count can't end up sleeping for an absurd amount of time.
+const maxRetryBackoff = 5 * time.Minute
+
+// retryBackoffDuration returns backoff * 2^attempt, capped at
+// maxRetryBackoff. attempt is 0 for the first retry.
+func retryBackoffDuration(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	d := backoff << uint(attempt)
+	if d <= 0 || d > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return d
+}
+
+// runWithRetry invokes exec (which applies --timeout itself, since each
+// attempt needs its own context) and, while the result is retryable under
+// --retry-on, sleeps an exponential backoff and tries again up to
+// opts.retry additional times. warn reports each retry attempt so
+// sequential and parallel callers can route it to the right destination.
+// ctx is the shutdown context (not a per-attempt derived one): if it's
+// canceled while waiting out a backoff, or between attempts, the retry loop
+// bails out immediately instead of sleeping out the remaining backoff chain.
+func runWithRetry(ctx context.Context, opts Options, warn func(format string, args ...interface{}), exec func() int) int {
+	exitCode := exec()
+	for attempt := 0; attempt < opts.retry && shouldRetry(exitCode, opts.retryOnCodes) && ctx.Err() == nil; attempt++ {
+		wait := retryBackoffDuration(opts.retryBackoff, attempt)
+		warn("[retry %d/%d after exit %d]\n", attempt+1, opts.retry, exitCode)
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return exitCode
+			}
+		}
+		exitCode = exec()
+	}
+	return exitCode
+}
+
+// validateOptions rejects flag combinations that cannot be satisfied together.
+func validateOptions(opts Options) error {
+	if opts.parallel > 1 && (opts.interval > 0 || opts.interactive) {
+		return fmt.Errorf("-P/--parallel > 1 cannot be combined with -t (interval) or -i (interactive)")
+	}
+	if opts.reportFormat != "" && opts.reportFormat != "json" && opts.reportFormat != "ndjson" {
+		return fmt.Errorf("invalid --report-format %q, must be json or ndjson", opts.reportFormat)
+	}
+	return nil
+}
+
+func (app *App) processStdin(ctx context.Context, opts Options, args []string) error {
+	if opts.parallel > 1 {
+		return app.processStdinParallel(ctx, opts, args)
+	}
+
+	scanner := newLineScanner(app.stdin, opts)
 	lastErrorCode := 0
 
 	for scanner.Scan() {
-		line := strings.TrimRight(scanner.Text(), "\n\r")
-		
+		if ctx.Err() != nil {
+			break
+		}
+
+		line := scanner.Text()
+		if !opts.nulDelim {
+			line = strings.TrimRight(line, "\n\r")
+		}
+
 		// Handle empty lines
 		if line == "" {
 			if opts.showWhat || opts.showCommand {
 				app.printWarning("[empty line]\n")
 			}
+			app.report(ReportEntry{Line: line, Skipped: true})
 			continue
 		}
 
@@ -231,18 +614,28 @@ func (app *App) processStdin(opts Options, args []string) error {
 		// Replace placeholders and prepare command
 		var commandDisplay string
 		var exitCode int
-		
+
 		if opts.shellMode {
 			// Shell mode: single string command
-			command := replacePlaceholders(args[0], line)
+			command, perr := replacePlaceholders(args[0], line, opts)
+			if perr != nil {
+				app.printError("%v\n", perr)
+				app.report(ReportEntry{Line: line, Command: args[0], ExitCode: 1})
+				lastErrorCode = 1
+				if !opts.forceContinue {
+					return fmt.Errorf("%v", perr)
+				}
+				continue
+			}
 			commandDisplay = command
-			
+
 			if opts.showCommand {
 				app.printCommand("> %s\n", command)
 			}
 
 			if opts.dryRun {
 				fmt.Fprintf(app.stdout, "%s\n", command)
+				app.report(ReportEntry{Line: line, Command: command, DryRun: true})
 				continue
 			}
 
@@ -251,25 +644,55 @@ func (app *App) processStdin(opts Options, args []string) error {
 				var response string
 				fmt.Scanln(&response)
 				if response != "y" && response != "Y" {
+					app.report(ReportEntry{Line: line, Command: command, Skipped: true})
 					continue
 				}
 			}
 
-			exitCode = app.executor.ExecuteShell(command)
+			start := time.Now()
+			exitCode = runWithRetry(ctx, opts, app.printWarning, func() int {
+				ctx, cancel := commandContext(ctx, opts)
+				defer cancel()
+				return app.executor.ExecuteShellCtx(ctx, command)
+			})
+			end := time.Now()
+			timedOut := exitCode == timeoutExitCode && opts.timeout > 0
+			if timedOut {
+				app.printWarning("[timeout after %s]\n", opts.timeout)
+			}
+			app.report(ReportEntry{
+				Line: line, Command: command,
+				StartTime: start, EndTime: end, DurationMs: end.Sub(start).Milliseconds(),
+				ExitCode: exitCode, TimedOut: timedOut,
+			})
 		} else {
 			// Direct mode: replace placeholders in each argument
 			commandArgs := make([]string, len(args))
+			var perr error
 			for i, arg := range args {
-				commandArgs[i] = replacePlaceholders(arg, line)
+				commandArgs[i], perr = replacePlaceholders(arg, line, opts)
+				if perr != nil {
+					break
+				}
+			}
+			if perr != nil {
+				app.printError("%v\n", perr)
+				app.report(ReportEntry{Line: line, Command: args, ExitCode: 1})
+				lastErrorCode = 1
+				if !opts.forceContinue {
+					return fmt.Errorf("%v", perr)
+				}
+				continue
 			}
 			commandDisplay = strings.Join(commandArgs, " ")
-			
+
 			if opts.showCommand {
 				app.printCommand("> %s\n", commandDisplay)
 			}
 
 			if opts.dryRun {
 				fmt.Fprintf(app.stdout, "%s\n", commandDisplay)
+				app.report(ReportEntry{Line: line, Command: commandArgs, DryRun: true})
 				continue
 			}
 
@@ -278,11 +701,27 @@ func (app *App) processStdin(opts Options, args []string) error {
 				var response string
 				fmt.Scanln(&response)
 				if response != "y" && response != "Y" {
+					app.report(ReportEntry{Line: line, Command: commandArgs, Skipped: true})
 					continue
 				}
 			}
 
-			exitCode = app.executor.ExecuteDirect(commandArgs)
+			start := time.Now()
+			exitCode = runWithRetry(ctx, opts, app.printWarning, func() int {
+				ctx, cancel := commandContext(ctx, opts)
+				defer cancel()
+				return app.executor.ExecuteDirectCtx(ctx, commandArgs)
+			})
+			end := time.Now()
+			timedOut := exitCode == timeoutExitCode && opts.timeout > 0
+			if timedOut {
+				app.printWarning("[timeout after %s]\n", opts.timeout)
+			}
+			app.report(ReportEntry{
+				Line: line, Command: commandArgs,
+				StartTime: start, EndTime: end, DurationMs: end.Sub(start).Milliseconds(),
+				ExitCode: exitCode, TimedOut: timedOut,
+			})
 		}
 
 		if opts.showCommand {
@@ -317,8 +756,354 @@ func (app *App) processStdin(opts Options, args []string) error {
 	return nil
 }
 
-func replacePlaceholders(template, input string) string {
-	return strings.ReplaceAll(template, "{}", input)
+// jobOutcome is the result of running one input line through the worker
+// pool: the buffered output it produced plus its exit code and position,
+// so processStdinParallel can flush it (in order, if requested) and fold
+// its exit code into the aggregate result.
+type jobOutcome struct {
+	index    int
+	exitCode int
+	stdout   []byte
+	stderr   []byte
+}
+
+// processStdinParallel is the -P/--parallel counterpart of processStdin. It
+// fans each input line out to a bounded pool of workers, buffers each job's
+// stdout/stderr so concurrent commands never interleave, and flushes those
+// buffers to app.stdout/app.stderr as jobs complete. With --keep-order,
+// flushing is delayed until every earlier-indexed job has already drained.
+// Without -f (force continue), the first failing job's flush cancels jobCtx (the
+// context passed to every runParallelJob call), so already-dispatched jobs
+// are interrupted rather than left to run to completion, in addition to
+// stopCh halting the dispatch of new ones. The line-reading goroutine and the
+// dispatch loop both also select on jobCtx.Done() directly, so a shutdown
+// signal (which cancels ctx, and so jobCtx, without ever touching stopCh)
+// stops new lines from being read or dispatched too.
+func (app *App) processStdinParallel(ctx context.Context, opts Options, args []string) error {
+	scanner := newLineScanner(app.stdin, opts)
+
+	type indexedLine struct {
+		index int
+		line  string
+	}
+
+	jobCtx, cancelJobs := context.WithCancel(ctx)
+	defer cancelJobs()
+
+	lines := make(chan indexedLine)
+	results := make(chan jobOutcome)
+	sem := make(chan struct{}, opts.parallel)
+	stopCh := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopCh)
+			cancelJobs()
+		})
+	}
+
+	go func() {
+		defer close(lines)
+		idx := 0
+		for scanner.Scan() {
+			if jobCtx.Err() != nil {
+				return
+			}
+
+			line := scanner.Text()
+			if !opts.nulDelim {
+				line = strings.TrimRight(line, "\n\r")
+			}
+			item := indexedLine{idx, line}
+			select {
+			case lines <- item:
+			case <-stopCh:
+				return
+			case <-jobCtx.Done():
+				return
+			}
+			idx++
+		}
+	}()
+
+	go func() {
+		var wg sync.WaitGroup
+		for job := range lines {
+			select {
+			case <-stopCh:
+				continue
+			case <-jobCtx.Done():
+				continue
+			default:
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(job indexedLine) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results <- app.runParallelJob(jobCtx, opts, args, job.index, job.line)
+			}(job)
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]jobOutcome)
+	nextFlush := 0
+	highestExit := 0
+	aborted := false
+
+	flush := func(o jobOutcome) {
+		app.stdout.Write(o.stdout)
+		app.stderr.Write(o.stderr)
+		if o.exitCode > highestExit {
+			highestExit = o.exitCode
+		}
+		if o.exitCode != 0 && !opts.forceContinue && !aborted {
+			aborted = true
+			stop()
+		}
+	}
+
+	for o := range results {
+		if !opts.keepOrder {
+			flush(o)
+			continue
+		}
+
+		pending[o.index] = o
+		for {
+			next, ok := pending[nextFlush]
+			if !ok {
+				break
+			}
+			flush(next)
+			delete(pending, nextFlush)
+			nextFlush++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading stdin: %v", err)
+	}
+
+	if aborted {
+		return fmt.Errorf("command failed with exit code %d", highestExit)
+	}
+
+	if highestExit != 0 {
+		return fmt.Errorf("commands completed with errors, highest exit code: %d", highestExit)
+	}
+
+	return nil
+}
+
+// runParallelJob executes a single input line in isolation, writing any
+// target/command/status output into job-local buffers via a throwaway App
+// so it can be flushed atomically alongside the command's own stdout/stderr.
+func (app *App) runParallelJob(ctx context.Context, opts Options, args []string, index int, line string) jobOutcome {
+	outBuf := &bytes.Buffer{}
+	errBuf := &bytes.Buffer{}
+	jobApp := &App{stdout: outBuf, stderr: errBuf, useColor: app.useColor}
+	outcome := jobOutcome{index: index}
+
+	finish := func(exitCode int) jobOutcome {
+		outcome.exitCode = exitCode
+		outcome.stdout = outBuf.Bytes()
+		outcome.stderr = errBuf.Bytes()
+		return outcome
+	}
+
+	if line == "" {
+		if opts.showWhat || opts.showCommand {
+			jobApp.printWarning("[empty line]\n")
+		}
+		app.report(ReportEntry{Line: line, Skipped: true})
+		return finish(0)
+	}
+
+	if opts.showWhat {
+		jobApp.printTarget("%s\n", line)
+	}
+
+	jobExecutor := app.executor
+	if be, ok := app.executor.(BufferedExecutor); ok {
+		jobExecutor = be.WithOutput(outBuf, errBuf)
+	}
+
+	var exitCode int
+	if opts.shellMode {
+		command, perr := replacePlaceholders(args[0], line, opts)
+		if perr != nil {
+			jobApp.printError("%v\n", perr)
+			app.report(ReportEntry{Line: line, Command: args[0], ExitCode: 1})
+			return finish(1)
+		}
+		if opts.showCommand {
+			jobApp.printCommand("> %s\n", command)
+		}
+		if opts.dryRun {
+			fmt.Fprintf(outBuf, "%s\n", command)
+			app.report(ReportEntry{Line: line, Command: command, DryRun: true})
+			return finish(0)
+		}
+		start := time.Now()
+		exitCode = runWithRetry(ctx, opts, jobApp.printWarning, func() int {
+			ctx, cancel := commandContext(ctx, opts)
+			defer cancel()
+			return jobExecutor.ExecuteShellCtx(ctx, command)
+		})
+		end := time.Now()
+		timedOut := exitCode == timeoutExitCode && opts.timeout > 0
+		if timedOut {
+			jobApp.printWarning("[timeout after %s]\n", opts.timeout)
+		}
+		app.report(ReportEntry{
+			Line: line, Command: command,
+			StartTime: start, EndTime: end, DurationMs: end.Sub(start).Milliseconds(),
+			ExitCode: exitCode, TimedOut: timedOut,
+		})
+	} else {
+		commandArgs := make([]string, len(args))
+		for i, arg := range args {
+			expanded, perr := replacePlaceholders(arg, line, opts)
+			if perr != nil {
+				jobApp.printError("%v\n", perr)
+				app.report(ReportEntry{Line: line, Command: args, ExitCode: 1})
+				return finish(1)
+			}
+			commandArgs[i] = expanded
+		}
+		commandDisplay := strings.Join(commandArgs, " ")
+		if opts.showCommand {
+			jobApp.printCommand("> %s\n", commandDisplay)
+		}
+		if opts.dryRun {
+			fmt.Fprintf(outBuf, "%s\n", commandDisplay)
+			app.report(ReportEntry{Line: line, Command: commandArgs, DryRun: true})
+			return finish(0)
+		}
+		start := time.Now()
+		exitCode = runWithRetry(ctx, opts, jobApp.printWarning, func() int {
+			ctx, cancel := commandContext(ctx, opts)
+			defer cancel()
+			return jobExecutor.ExecuteDirectCtx(ctx, commandArgs)
+		})
+		end := time.Now()
+		timedOut := exitCode == timeoutExitCode && opts.timeout > 0
+		if timedOut {
+			jobApp.printWarning("[timeout after %s]\n", opts.timeout)
+		}
+		app.report(ReportEntry{
+			Line: line, Command: commandArgs,
+			StartTime: start, EndTime: end, DurationMs: end.Sub(start).Milliseconds(),
+			ExitCode: exitCode, TimedOut: timedOut,
+		})
+	}
+
+	if opts.showCommand {
+		if exitCode == 0 {
+			jobApp.printSuccess("[exit: %d]\n", exitCode)
+		} else {
+			jobApp.printError("[exit: %d]\n", exitCode)
+		}
+	}
+
+	return finish(exitCode)
+}
+
+// scanNulDelimited is a bufio.SplitFunc that splits on NUL bytes instead of
+// newlines, mirroring bufio.ScanLines, for consuming `find -print0` output
+// safely even when filenames themselves contain newlines or spaces.
+func scanNulDelimited(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[0:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// newLineScanner builds the bufio.Scanner processStdin(Parallel) reads
+// input records from, switching to NUL-delimited splitting under -0.
+func newLineScanner(r io.Reader, opts Options) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	if opts.nulDelim {
+		scanner.Split(scanNulDelimited)
+	}
+	return scanner
+}
+
+// splitFields splits a line into the fields {1}, {2}, ... refer to. The
+// default delimiter (" ") splits on runs of whitespace like shell word
+// splitting; any other delimiter is matched literally.
+func splitFields(line, delimiter string) []string {
+	if delimiter == "" || delimiter == " " {
+		return strings.Fields(line)
+	}
+	return strings.Split(line, delimiter)
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// replacePlaceholders expands the placeholder tokens in template against a
+// single input line: {} is the whole line (kept as a synonym for backwards
+// compatibility), {1}/{2}/... are fields split from the line by
+// opts.delimiter, and {basename}/{dirname}/{ext}/{.} are the line treated
+// as a path and derived via path/filepath. Referencing a numbered field
+// past the end of the line is reported as an error rather than silently
+// expanding to an empty string.
+func replacePlaceholders(template, input string, opts Options) (string, error) {
+	var fields []string
+	fieldsSplit := false
+	var splitErr error
+
+	result := placeholderPattern.ReplaceAllStringFunc(template, func(token string) string {
+		if splitErr != nil {
+			return token
+		}
+
+		name := token[1 : len(token)-1]
+		switch name {
+		case "":
+			return input
+		case "basename":
+			return filepath.Base(input)
+		case "dirname":
+			return filepath.Dir(input)
+		case "ext":
+			return filepath.Ext(input)
+		case ".":
+			base := filepath.Base(input)
+			return strings.TrimSuffix(base, filepath.Ext(base))
+		}
+
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			// Not a recognized placeholder; leave it untouched.
+			return token
+		}
+
+		if !fieldsSplit {
+			fields = splitFields(input, opts.delimiter)
+			fieldsSplit = true
+		}
+		if n < 1 || n > len(fields) {
+			splitErr = fmt.Errorf("placeholder {%d} has no matching field in %q (got %d field(s))", n, input, len(fields))
+			return token
+		}
+		return fields[n-1]
+	})
+
+	if splitErr != nil {
+		return "", splitErr
+	}
+	return result, nil
 }
 
 
@@ -2,16 +2,33 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-// MockCommandExecutor is a mock implementation of CommandExecutor for testing
+// MockCommandExecutor is a mock implementation of CommandExecutor for testing.
+// Setting sleep simulates a slow command: ExecuteShellCtx/ExecuteDirectCtx
+// block for that long (or until ctx is canceled) before returning exitCode,
+// or timeoutExitCode if ctx's deadline fired first, which is what --timeout
+// tests use to force a deadline to fire. Setting
+// exitCodes instead scripts a sequence of exit codes, one per call (the
+// last entry repeats once exhausted), which --retry tests use to model
+// "succeeds on 2nd try" / "exhausts retries".
 type MockCommandExecutor struct {
+	mu             sync.Mutex
 	shellCommands  []string
 	directCommands [][]string
 	exitCode       int
+	exitCodes      []int
+	callCount      int
+	sleep          time.Duration
 }
 
 func NewMockCommandExecutor(exitCode int) *MockCommandExecutor {
@@ -21,16 +38,69 @@ func NewMockCommandExecutor(exitCode int) *MockCommandExecutor {
 }
 
 func (m *MockCommandExecutor) ExecuteShell(command string) int {
+	return m.ExecuteShellCtx(context.Background(), command)
+}
+
+func (m *MockCommandExecutor) ExecuteShellCtx(ctx context.Context, command string) int {
+	m.mu.Lock()
 	m.shellCommands = append(m.shellCommands, command)
-	return m.exitCode
+	m.mu.Unlock()
+	return m.await(ctx)
 }
 
 func (m *MockCommandExecutor) ExecuteDirect(args []string) int {
+	return m.ExecuteDirectCtx(context.Background(), args)
+}
+
+func (m *MockCommandExecutor) ExecuteDirectCtx(ctx context.Context, args []string) int {
 	// Create a copy of args to avoid slice mutation issues
 	argsCopy := make([]string, len(args))
 	copy(argsCopy, args)
+
+	m.mu.Lock()
 	m.directCommands = append(m.directCommands, argsCopy)
-	return m.exitCode
+	m.mu.Unlock()
+	return m.await(ctx)
+}
+
+// nextExitCodeLocked returns the next scripted exit code, holding steady on
+// the last one once exitCodes is exhausted. Callers must hold m.mu.
+func (m *MockCommandExecutor) nextExitCodeLocked() int {
+	if len(m.exitCodes) == 0 {
+		return m.exitCode
+	}
+	idx := m.callCount
+	if idx >= len(m.exitCodes) {
+		idx = len(m.exitCodes) - 1
+	}
+	m.callCount++
+	return m.exitCodes[idx]
+}
+
+// await blocks for the configured sleep, simulating a slow command, but
+// returns early with the timeout exit code if ctx's deadline fires first.
+func (m *MockCommandExecutor) await(ctx context.Context) int {
+	m.mu.Lock()
+	sleep := m.sleep
+	code := m.nextExitCodeLocked()
+	m.mu.Unlock()
+
+	if sleep <= 0 {
+		return code
+	}
+
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return code
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return timeoutExitCode
+		}
+		return code
+	}
 }
 
 func TestReplacePlaceholders(t *testing.T) {
@@ -68,7 +138,39 @@ func TestReplacePlaceholders(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := replacePlaceholders(tt.template, tt.input)
+			result, err := replacePlaceholders(tt.template, tt.input, Options{delimiter: " "})
+			if err != nil {
+				t.Fatalf("replacePlaceholders(%q, %q) returned unexpected error: %v", tt.template, tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("replacePlaceholders(%q, %q) = %q, want %q", tt.template, tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReplacePlaceholders_NumberedAndNamedTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		input     string
+		delimiter string
+		expected  string
+	}{
+		{"numbered fields split on whitespace", "mv {1} {2}", "a.txt b.txt", " ", "mv a.txt b.txt"},
+		{"numbered fields split on custom delimiter", "mv {1} {2}", "a.txt,b.txt", ",", "mv a.txt b.txt"},
+		{"basename", "echo {basename}", "/tmp/dir/file.tar.gz", " ", "echo file.tar.gz"},
+		{"dirname", "echo {dirname}", "/tmp/dir/file.txt", " ", "echo /tmp/dir"},
+		{"ext", "echo {ext}", "/tmp/dir/file.tar.gz", " ", "echo .gz"},
+		{"basename without extension", "echo {.}", "/tmp/dir/file.tar.gz", " ", "echo file.tar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := replacePlaceholders(tt.template, tt.input, Options{delimiter: tt.delimiter})
+			if err != nil {
+				t.Fatalf("replacePlaceholders(%q, %q) returned unexpected error: %v", tt.template, tt.input, err)
+			}
 			if result != tt.expected {
 				t.Errorf("replacePlaceholders(%q, %q) = %q, want %q", tt.template, tt.input, result, tt.expected)
 			}
@@ -76,6 +178,36 @@ func TestReplacePlaceholders(t *testing.T) {
 	}
 }
 
+func TestReplacePlaceholders_MissingFieldIsError(t *testing.T) {
+	_, err := replacePlaceholders("mv {1} {2}", "only-one-field", Options{delimiter: " "})
+	if err == nil {
+		t.Fatal("Expected an error when a numbered placeholder has no matching field")
+	}
+}
+
+func TestScanNulDelimited(t *testing.T) {
+	stdin := strings.NewReader("a b.txt\x00c d.txt\x00")
+	scanner := newLineScanner(stdin, Options{nulDelim: true})
+
+	var records []string
+	for scanner.Scan() {
+		records = append(records, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected scan error: %v", err)
+	}
+
+	expected := []string{"a b.txt", "c d.txt"}
+	if len(records) != len(expected) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(expected), records)
+	}
+	for i, want := range expected {
+		if records[i] != want {
+			t.Errorf("record %d = %q, want %q", i, records[i], want)
+		}
+	}
+}
+
 func TestProcessStdin_DirectMode_DryRun(t *testing.T) {
 	stdin := strings.NewReader("file1.txt\nfile2.txt\n")
 	stdout := &bytes.Buffer{}
@@ -96,7 +228,7 @@ func TestProcessStdin_DirectMode_DryRun(t *testing.T) {
 	}
 	args := []string{"echo", "{}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err != nil {
 		t.Fatalf("processStdin failed: %v", err)
 	}
@@ -132,7 +264,7 @@ func TestProcessStdin_DirectMode_Execution(t *testing.T) {
 	}
 	args := []string{"echo", "{}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err != nil {
 		t.Fatalf("processStdin failed: %v", err)
 	}
@@ -181,7 +313,7 @@ func TestProcessStdin_ShellMode_DryRun(t *testing.T) {
 	}
 	args := []string{"echo hello {} && echo processed {}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err != nil {
 		t.Fatalf("processStdin failed: %v", err)
 	}
@@ -217,7 +349,7 @@ func TestProcessStdin_ShellMode_Execution(t *testing.T) {
 	}
 	args := []string{"echo hello {}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err != nil {
 		t.Fatalf("processStdin failed: %v", err)
 	}
@@ -261,7 +393,7 @@ func TestProcessStdin_EmptyLines(t *testing.T) {
 	}
 	args := []string{"echo", "{}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err != nil {
 		t.Fatalf("processStdin failed: %v", err)
 	}
@@ -304,7 +436,7 @@ func TestProcessStdin_ErrorHandling(t *testing.T) {
 	}
 	args := []string{"echo", "{}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	if err == nil {
 		t.Fatal("Expected error due to command failure, got nil")
 	}
@@ -336,7 +468,7 @@ func TestProcessStdin_ForceContinueOnError(t *testing.T) {
 	}
 	args := []string{"echo", "{}"}
 
-	err := app.processStdin(opts, args)
+	err := app.processStdin(context.Background(), opts, args)
 	// In force continue mode, we expect an error indicating there were command failures
 	// but processing continued
 	if err == nil {
@@ -425,6 +557,693 @@ func TestShouldUseColor(t *testing.T) {
 	}
 }
 
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"sequential is always fine", Options{parallel: 1, interval: 5, interactive: true}, false},
+		{"parallel alone is fine", Options{parallel: 4}, false},
+		{"parallel with interval conflicts", Options{parallel: 4, interval: 1}, true},
+		{"parallel with interactive conflicts", Options{parallel: 4, interactive: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOptions(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessStdin_Parallel_Execution(t *testing.T) {
+	stdin := strings.NewReader("file1.txt\nfile2.txt\nfile3.txt\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		parallel: 3,
+	}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+
+	if len(executor.directCommands) != 3 {
+		t.Fatalf("Expected 3 commands, got %d", len(executor.directCommands))
+	}
+}
+
+func TestProcessStdin_Parallel_KeepOrder(t *testing.T) {
+	stdin := strings.NewReader("1\n2\n3\n4\n5\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		parallel:  4,
+		keepOrder: true,
+		dryRun:    true,
+	}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+
+	expected := "echo 1\necho 2\necho 3\necho 4\necho 5\n"
+	if stdout.String() != expected {
+		t.Errorf("stdout = %q, want %q (keep-order flush must match input order)", stdout.String(), expected)
+	}
+}
+
+func TestProcessStdin_Parallel_AbortsOnError(t *testing.T) {
+	stdin := strings.NewReader("file1\nfile2\nfile3\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(1)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		parallel:      2,
+		forceContinue: false,
+	}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err == nil {
+		t.Fatal("Expected error due to command failure, got nil")
+	}
+}
+
+// failFastSleepExecutor returns exit code 1 immediately for the line "fail"
+// and otherwise blocks for sleep (or until ctx is canceled), so tests can
+// tell a sibling job was actually interrupted rather than left to run to
+// completion.
+type failFastSleepExecutor struct {
+	sleep time.Duration
+}
+
+func (e *failFastSleepExecutor) ExecuteShell(command string) int {
+	return e.ExecuteShellCtx(context.Background(), command)
+}
+
+func (e *failFastSleepExecutor) ExecuteShellCtx(ctx context.Context, command string) int {
+	return e.await(ctx, command == "fail")
+}
+
+func (e *failFastSleepExecutor) ExecuteDirect(args []string) int {
+	return e.ExecuteDirectCtx(context.Background(), args)
+}
+
+func (e *failFastSleepExecutor) ExecuteDirectCtx(ctx context.Context, args []string) int {
+	return e.await(ctx, len(args) > 1 && args[1] == "fail")
+}
+
+func (e *failFastSleepExecutor) await(ctx context.Context, fail bool) int {
+	if fail {
+		return 1
+	}
+	select {
+	case <-time.After(e.sleep):
+		return 0
+	case <-ctx.Done():
+		return 0
+	}
+}
+
+// TestProcessStdin_Parallel_AbortCancelsInFlightJobs verifies that, on the
+// first failing job, processStdinParallel cancels the context passed to
+// already-dispatched sibling jobs rather than letting them run to natural
+// completion: one fast failure alongside several 200ms sleeping jobs under
+// -P should return in well under 200ms.
+func TestProcessStdin_Parallel_AbortCancelsInFlightJobs(t *testing.T) {
+	stdin := strings.NewReader("fail\nslow\nslow\nslow\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := &failFastSleepExecutor{sleep: 200 * time.Millisecond}
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		parallel:      4,
+		forceContinue: false,
+	}
+	args := []string{"echo", "{}"}
+
+	start := time.Now()
+	if err := app.processStdin(context.Background(), opts, args); err == nil {
+		t.Fatal("Expected error due to command failure, got nil")
+	}
+	elapsed := time.Since(start)
+
+	if elapsed >= executor.sleep {
+		t.Errorf("aborting on a failed job should cancel in-flight siblings early, but processStdin took %v (full sleep was %v)", elapsed, executor.sleep)
+	}
+}
+
+func TestResolveExitCode(t *testing.T) {
+	t.Run("nil error means success", func(t *testing.T) {
+		if code := resolveExitCode(context.Background(), nil); code != 0 {
+			t.Errorf("resolveExitCode(nil) = %d, want 0", code)
+		}
+	})
+
+	t.Run("deadline exceeded reports timeout code", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+
+		if code := resolveExitCode(ctx, ctx.Err()); code != timeoutExitCode {
+			t.Errorf("resolveExitCode(deadline exceeded) = %d, want %d", code, timeoutExitCode)
+		}
+	})
+
+	t.Run("non-exec error falls back to 1", func(t *testing.T) {
+		if code := resolveExitCode(context.Background(), fmt.Errorf("boom")); code != 1 {
+			t.Errorf("resolveExitCode(generic error) = %d, want 1", code)
+		}
+	})
+}
+
+func TestProcessStdin_Timeout(t *testing.T) {
+	stdin := strings.NewReader("slow\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+	executor.sleep = 50 * time.Millisecond
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		forceContinue: true,
+		timeout:       5 * time.Millisecond,
+	}
+	args := []string{"echo", "{}"}
+
+	err := app.processStdin(context.Background(), opts, args)
+	if err == nil {
+		t.Fatal("Expected an error because the command timed out")
+	}
+	if !strings.Contains(stderr.String(), "timeout") {
+		t.Errorf("Expected a timeout warning on stderr, got: %q", stderr.String())
+	}
+}
+
+func TestProcessStdin_TimeoutDoesNotFireWhenFast(t *testing.T) {
+	stdin := strings.NewReader("fast\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		timeout: time.Second,
+	}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+}
+
+// TestProcessStdin_ShutdownContextCancelsInFlightCommand mimics main's
+// SIGINT/SIGTERM handling: canceling the context passed into processStdin
+// should interrupt a running command rather than waiting for it to finish
+// on its own, the same way runInProcessGroup reacts to ctx.Done() for a
+// real command.
+func TestProcessStdin_ShutdownContextCancelsInFlightCommand(t *testing.T) {
+	stdin := strings.NewReader("slow\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+	executor.sleep = 200 * time.Millisecond
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	opts := Options{forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	start := time.Now()
+	app.processStdin(ctx, opts, args)
+	elapsed := time.Since(start)
+
+	if elapsed >= executor.sleep {
+		t.Errorf("canceling the shutdown context should interrupt the in-flight command early, but processStdin took %v (full sleep was %v)", elapsed, executor.sleep)
+	}
+}
+
+// TestProcessStdin_ShutdownStopsReadingNewLines verifies that once the
+// shutdown context is canceled, processStdin stops dispatching new input
+// lines rather than reading and running the rest of stdin (each one just to
+// have it immediately interrupted).
+func TestProcessStdin_ShutdownStopsReadingNewLines(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		input.WriteString("line\n")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+	executor.sleep = 5 * time.Millisecond
+
+	app := &App{
+		stdin:    strings.NewReader(input.String()),
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	opts := Options{forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	app.processStdin(ctx, opts, args)
+
+	if len(executor.directCommands) >= 1000 {
+		t.Errorf("shutdown should stop reading new stdin lines, but all %d lines were dispatched", len(executor.directCommands))
+	}
+}
+
+// TestProcessStdin_Parallel_ShutdownStopsReadingNewLines is the -P
+// counterpart of TestProcessStdin_ShutdownStopsReadingNewLines: the
+// line-reading goroutine and dispatch loop must stop on the shutdown
+// context too, not just on a command-failure-driven stop().
+func TestProcessStdin_Parallel_ShutdownStopsReadingNewLines(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		input.WriteString("line\n")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+	executor.sleep = 5 * time.Millisecond
+
+	app := &App{
+		stdin:    strings.NewReader(input.String()),
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	opts := Options{parallel: 4, forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	app.processStdin(ctx, opts, args)
+
+	if len(executor.directCommands) >= 1000 {
+		t.Errorf("shutdown should stop reading new stdin lines, but all %d lines were dispatched", len(executor.directCommands))
+	}
+}
+
+// TestRunWithRetry_ShutdownAbortsBackoff verifies that a canceled shutdown
+// context stops the retry loop instead of sleeping out the remaining
+// backoff chain, whether the context is already canceled before the first
+// attempt or fires while a backoff sleep is in progress.
+func TestRunWithRetry_ShutdownAbortsBackoff(t *testing.T) {
+	t.Run("already-canceled context skips remaining attempts", func(t *testing.T) {
+		opts := Options{retry: 3, retryBackoff: 300 * time.Millisecond}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		exitCode := runWithRetry(ctx, opts, func(string, ...interface{}) {}, func() int {
+			calls++
+			return 1
+		})
+
+		if calls != 1 {
+			t.Errorf("expected exec to run once before the retry loop bails out, got %d calls", calls)
+		}
+		if exitCode != 1 {
+			t.Errorf("exitCode = %d, want 1", exitCode)
+		}
+	})
+
+	t.Run("cancellation mid-backoff cuts the wait short", func(t *testing.T) {
+		opts := Options{retry: 3, retryBackoff: 300 * time.Millisecond}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		runWithRetry(ctx, opts, func(string, ...interface{}) {}, func() int { return 1 })
+		elapsed := time.Since(start)
+
+		if elapsed >= opts.retryBackoff {
+			t.Errorf("canceling mid-backoff should cut the wait short, but runWithRetry took %v (a full backoff chain would be much longer)", elapsed)
+		}
+	})
+}
+
+// TestProcessStdinParallel_RealExecutor_NoInterleaving is the only test that
+// exercises RealCommandExecutor/WithOutput under -P: each job's shell
+// command emits several lines with a small sleep between them, which would
+// interleave mid-block if per-job buffering weren't actually isolating
+// concurrent sh -c output.
+func TestProcessStdinParallel_RealExecutor_NoInterleaving(t *testing.T) {
+	stdin := strings.NewReader("a\nb\nc\nd\ne\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewRealCommandExecutor(io.Discard, io.Discard, 5*time.Second)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{
+		parallel:  5,
+		shellMode: true,
+	}
+	args := []string{"for i in 1 2 3; do echo {}-$i; sleep 0.01; done"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 15 {
+		t.Fatalf("expected 15 output lines (5 jobs x 3 lines each), got %d: %q", len(lines), stdout.String())
+	}
+
+	for i := 0; i < len(lines); i += 3 {
+		block := lines[i : i+3]
+		job := strings.SplitN(block[0], "-", 2)[0]
+		for _, l := range block {
+			if !strings.HasPrefix(l, job+"-") {
+				t.Fatalf("job output interleaved: block %v does not all share prefix %q", block, job+"-")
+			}
+		}
+	}
+}
+
+func TestReporter_NdjsonStreamsEntriesAndSummary(t *testing.T) {
+	stdin := strings.NewReader("file1.txt\nfile2.txt\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	reportBuf := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		reporter: NewReporter(reportBuf, "ndjson"),
+	}
+
+	opts := Options{}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+	app.reporter.Close()
+
+	lines := strings.Split(strings.TrimSpace(reportBuf.String()), "\n")
+	if len(lines) != 3 { // two entries + one summary
+		t.Fatalf("expected 3 NDJSON lines (2 entries + summary), got %d: %q", len(lines), reportBuf.String())
+	}
+
+	var first ReportEntry
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("failed to unmarshal first entry: %v", err)
+	}
+	if first.Line != "file1.txt" || first.ExitCode != 0 {
+		t.Errorf("first entry = %+v, want line file1.txt with exit code 0", first)
+	}
+
+	var summary struct {
+		Summary reportSummary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+		t.Fatalf("failed to unmarshal summary: %v", err)
+	}
+	if summary.Summary.OK != 2 || summary.Summary.Fail != 0 {
+		t.Errorf("summary = %+v, want ok=2 fail=0", summary.Summary)
+	}
+}
+
+func TestReporter_JsonFormatBuffersEntries(t *testing.T) {
+	stdin := strings.NewReader("file1\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	reportBuf := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(1)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		reporter: NewReporter(reportBuf, "json"),
+	}
+
+	opts := Options{forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err == nil {
+		t.Fatal("expected an error due to command failure")
+	}
+	app.reporter.Close()
+
+	var payload struct {
+		Entries []ReportEntry `json:"entries"`
+		Summary reportSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(reportBuf.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to unmarshal json report: %v", err)
+	}
+	if len(payload.Entries) != 1 || payload.Entries[0].ExitCode != 1 {
+		t.Errorf("entries = %+v, want one entry with exit code 1", payload.Entries)
+	}
+	if payload.Summary.Fail != 1 || payload.Summary.HighestExitCode != 1 {
+		t.Errorf("summary = %+v, want fail=1 highest_exit_code=1", payload.Summary)
+	}
+}
+
+func TestParseRetryOn(t *testing.T) {
+	t.Run("empty means any non-zero", func(t *testing.T) {
+		codes, err := parseRetryOn("")
+		if err != nil || codes != nil {
+			t.Errorf("parseRetryOn(\"\") = %v, %v; want nil, nil", codes, err)
+		}
+	})
+
+	t.Run("parses comma-separated codes", func(t *testing.T) {
+		codes, err := parseRetryOn("1, 2,124")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []int{1, 2, 124}
+		if len(codes) != len(want) {
+			t.Fatalf("got %v, want %v", codes, want)
+		}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Errorf("codes[%d] = %d, want %d", i, codes[i], want[i])
+			}
+		}
+	})
+
+	t.Run("rejects non-numeric codes", func(t *testing.T) {
+		if _, err := parseRetryOn("oops"); err == nil {
+			t.Fatal("expected an error for a non-numeric exit code")
+		}
+	})
+}
+
+func TestShouldRetry(t *testing.T) {
+	if shouldRetry(0, nil) {
+		t.Error("exit code 0 should never be retried")
+	}
+	if !shouldRetry(1, nil) {
+		t.Error("any non-zero exit code should be retried when retryOn is empty")
+	}
+	if !shouldRetry(2, []int{1, 2}) {
+		t.Error("exit code 2 should be retried when it is in retryOn")
+	}
+	if shouldRetry(3, []int{1, 2}) {
+		t.Error("exit code 3 should not be retried when it is not in retryOn")
+	}
+}
+
+func TestRetryBackoffDuration(t *testing.T) {
+	if d := retryBackoffDuration(0, 0); d != 0 {
+		t.Errorf("zero backoff should stay zero, got %v", d)
+	}
+
+	base := 100 * time.Millisecond
+	if d := retryBackoffDuration(base, 0); d != base {
+		t.Errorf("retryBackoffDuration(base, 0) = %v, want %v", d, base)
+	}
+	if d := retryBackoffDuration(base, 1); d != 2*base {
+		t.Errorf("retryBackoffDuration(base, 1) = %v, want %v", d, 2*base)
+	}
+	if d := retryBackoffDuration(time.Hour, 10); d != maxRetryBackoff {
+		t.Errorf("large backoff should be capped at %v, got %v", maxRetryBackoff, d)
+	}
+}
+
+func TestProcessStdin_RetrySucceedsOnSecondTry(t *testing.T) {
+	stdin := strings.NewReader("file1\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(0)
+	executor.exitCodes = []int{1, 0}
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{retry: 2}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err != nil {
+		t.Fatalf("processStdin failed: %v", err)
+	}
+	if len(executor.directCommands) != 2 {
+		t.Fatalf("expected 2 attempts (1 failure + 1 success), got %d", len(executor.directCommands))
+	}
+	if !strings.Contains(stderr.String(), "[retry 1/2") {
+		t.Errorf("expected a retry notice on stderr, got: %q", stderr.String())
+	}
+}
+
+func TestProcessStdin_RetryExhausted(t *testing.T) {
+	stdin := strings.NewReader("file1\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(1)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{retry: 2, forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	err := app.processStdin(context.Background(), opts, args)
+	if err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if len(executor.directCommands) != 3 { // 1 initial + 2 retries
+		t.Fatalf("expected 3 attempts (1 initial + 2 retries), got %d", len(executor.directCommands))
+	}
+}
+
+func TestProcessStdin_RetryOnRestrictsRetryableCodes(t *testing.T) {
+	stdin := strings.NewReader("file1\n")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	executor := NewMockCommandExecutor(2)
+
+	app := &App{
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		executor: executor,
+		useColor: false,
+	}
+
+	opts := Options{retry: 3, retryOnCodes: []int{1}, forceContinue: true}
+	args := []string{"echo", "{}"}
+
+	if err := app.processStdin(context.Background(), opts, args); err == nil {
+		t.Fatal("expected an error since the command always fails")
+	}
+	if len(executor.directCommands) != 1 {
+		t.Errorf("exit code 2 is not in retry-on, so no retries should happen; got %d attempts", len(executor.directCommands))
+	}
+}
+
 func TestColorOutput(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}